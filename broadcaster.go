@@ -0,0 +1,88 @@
+package main
+
+import "sync"
+
+// WriteBroadcaster implements io.Writer, fanning every write out to any
+// number of registered listener channels. It backs both the per-process
+// output stream and the supervisor-wide event stream.
+type WriteBroadcaster struct {
+	mu        sync.Mutex
+	listeners map[string]chan string
+	chanSize  int
+
+	history    []string
+	historyCap int
+}
+
+// NewWriteBroadcaster creates a broadcaster. chanSize controls the buffer
+// size of each listener channel created by NewChanString.
+func NewWriteBroadcaster(chanSize int) *WriteBroadcaster {
+	return NewWriteBroadcasterWithHistory(chanSize, 0)
+}
+
+// NewWriteBroadcasterWithHistory is like NewWriteBroadcaster but also
+// keeps the last historyCap writes so new listeners can optionally be
+// replayed that backlog (see NewChanStringWithHistory).
+func NewWriteBroadcasterWithHistory(chanSize, historyCap int) *WriteBroadcaster {
+	return &WriteBroadcaster{
+		listeners:  make(map[string]chan string),
+		chanSize:   chanSize,
+		historyCap: historyCap,
+	}
+}
+
+func (b *WriteBroadcaster) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg := string(p)
+	if b.historyCap > 0 {
+		b.history = append(b.history, msg)
+		if len(b.history) > b.historyCap {
+			b.history = b.history[len(b.history)-b.historyCap:]
+		}
+	}
+	for _, c := range b.listeners {
+		select {
+		case c <- msg:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// NewChanString registers a new listener under id and returns its
+// channel. It never replays buffered history; use
+// NewChanStringWithHistory for that.
+func (b *WriteBroadcaster) NewChanString(id string) chan string {
+	return b.newListener(id, true)
+}
+
+// NewChanStringWithHistory registers a new listener under id. Unless
+// skipHistory is set, any buffered messages are pushed onto the channel
+// before it starts receiving live writes.
+func (b *WriteBroadcaster) NewChanStringWithHistory(id string, skipHistory bool) chan string {
+	return b.newListener(id, skipHistory)
+}
+
+func (b *WriteBroadcaster) newListener(id string, skipHistory bool) chan string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan string, b.chanSize+len(b.history))
+	if !skipHistory {
+		for _, msg := range b.history {
+			c <- msg
+		}
+	}
+	b.listeners[id] = c
+	return c
+}
+
+// CloseWriter unregisters and closes the listener channel for id.
+func (b *WriteBroadcaster) CloseWriter(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.listeners[id]; ok {
+		close(c)
+		delete(b.listeners, id)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// CronScheduler registers Program.Cron entries with an embedded cron.Cron
+// and fires StartEvent on their Process at each tick.
+type CronScheduler struct {
+	s    *Supervisor
+	cron *cron.Cron
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID
+	lastRun map[string]time.Time
+}
+
+func newCronScheduler(s *Supervisor) *CronScheduler {
+	return &CronScheduler{
+		s:       s,
+		cron:    cron.New(),
+		entries: make(map[string]cron.EntryID),
+		lastRun: make(map[string]time.Time),
+	}
+}
+
+func (cs *CronScheduler) Start() { cs.cron.Start() }
+func (cs *CronScheduler) Stop()  { cs.cron.Stop() }
+
+// schedule registers pg with the scheduler if it declares a Cron
+// expression, replacing any previous entry for the same name. If pg no
+// longer declares one, it de-registers whatever entry existed.
+func (cs *CronScheduler) schedule(pg Program) error {
+	cs.unschedule(pg.Name)
+	if pg.Cron == "" {
+		return nil
+	}
+	id, err := cs.cron.AddFunc(pg.Cron, func() { cs.trigger(pg.Name) })
+	if err != nil {
+		return fmt.Errorf("program %s: invalid cron expression %q: %v", pg.Name, pg.Cron, err)
+	}
+	cs.mu.Lock()
+	cs.entries[pg.Name] = id
+	cs.mu.Unlock()
+	return nil
+}
+
+// unschedule removes any cron entry registered for name.
+func (cs *CronScheduler) unschedule(name string) {
+	cs.mu.Lock()
+	id, ok := cs.entries[name]
+	delete(cs.entries, name)
+	cs.mu.Unlock()
+	if ok {
+		cs.cron.Remove(id)
+	}
+}
+
+// trigger starts name's process, as either a scheduled tick or a manual
+// call from hTriggerProgram.
+func (cs *CronScheduler) trigger(name string) error {
+	proc, ok := cs.s.procMap[name]
+	if !ok {
+		return fmt.Errorf("no such program: %s", name)
+	}
+	cs.mu.Lock()
+	cs.lastRun[name] = time.Now()
+	cs.mu.Unlock()
+	cs.s.broadcastEvent(name + " cron triggered")
+	proc.Operate(StartEvent)
+	return nil
+}
+
+// nextRun returns the next scheduled run time for name, if it has a cron
+// entry.
+func (cs *CronScheduler) nextRun(name string) (time.Time, bool) {
+	cs.mu.Lock()
+	id, ok := cs.entries[name]
+	cs.mu.Unlock()
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, e := range cs.cron.Entries() {
+		if e.ID == id {
+			return e.Next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lastRunTime returns the last time the scheduler triggered name.
+func (cs *CronScheduler) lastRunTime(name string) (time.Time, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	t, ok := cs.lastRun[name]
+	return t, ok
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qiniu/log"
+)
+
+// topoSort orders pgs so that every program appears after everything in
+// its DependsOn, or returns an error if the dependency graph has a cycle
+// or refers to an unknown program.
+func topoSort(pgs []Program) ([]string, error) {
+	byName := make(map[string]Program, len(pgs))
+	for _, pg := range pgs {
+		byName[pg.Name] = pg
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(pgs))
+	order := make([]string, 0, len(pgs))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected at program %q", name)
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("program %q depends on unknown program %q", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, pg := range pgs {
+		if err := visit(pg.Name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// groupMembers returns the names of every program in group, dependencies
+// first.
+func (s *Supervisor) groupMembers(group string) ([]string, error) {
+	pgs := s.programs()
+	order, err := topoSort(pgs)
+	if err != nil {
+		return nil, err
+	}
+	inGroup := make(map[string]bool)
+	for _, pg := range pgs {
+		if pg.Group == group {
+			inGroup[pg.Name] = true
+		}
+	}
+	if len(inGroup) == 0 {
+		return nil, fmt.Errorf("no such group: %s", group)
+	}
+	members := make([]string, 0, len(inGroup))
+	for _, name := range order {
+		if inGroup[name] {
+			members = append(members, name)
+		}
+	}
+	return members, nil
+}
+
+// startGroup starts every member of group in dependency order, waiting
+// for each to reach Running and stay there for its StartSeconds before
+// starting the next.
+func (s *Supervisor) startGroup(group string) error {
+	members, err := s.groupMembers(group)
+	if err != nil {
+		return err
+	}
+	s.broadcastEvent(fmt.Sprintf("group %s starting", group))
+	for _, name := range members {
+		proc, ok := s.procMap[name]
+		if !ok {
+			continue
+		}
+		proc.Operate(StartEvent)
+		if err := s.waitRunning(proc); err != nil {
+			return fmt.Errorf("group %s: %v", group, err)
+		}
+	}
+	s.broadcastEvent(fmt.Sprintf("group %s ready", group))
+	return nil
+}
+
+// waitRunning blocks until proc reports Running and has held that state
+// for its configured StartSeconds.
+func (s *Supervisor) waitRunning(proc *Process) error {
+	const pollInterval = 100 * time.Millisecond
+	startWait := time.Duration(proc.Program.StartSeconds) * time.Second
+
+	deadline := time.Now().Add(startWait + 10*time.Second)
+	for proc.State() != StateRunning {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("program %s did not reach running state", proc.Name)
+		}
+		time.Sleep(pollInterval)
+	}
+	if startWait > 0 {
+		time.Sleep(startWait)
+		if proc.State() != StateRunning {
+			return fmt.Errorf("program %s exited before StartSeconds elapsed", proc.Name)
+		}
+	}
+	return nil
+}
+
+// stopGroup stops every member of group in reverse dependency order:
+// dependents before the dependencies they rely on.
+func (s *Supervisor) stopGroup(group string) error {
+	members, err := s.groupMembers(group)
+	if err != nil {
+		return err
+	}
+	s.broadcastEvent(fmt.Sprintf("group %s stopping", group))
+	for i := len(members) - 1; i >= 0; i-- {
+		s.stopAndWait(members[i])
+	}
+	s.broadcastEvent(fmt.Sprintf("group %s stopped", group))
+	return nil
+}
+
+// shutdownOrder returns all program names in reverse dependency order,
+// falling back to an arbitrary order if the graph can't be sorted (it was
+// already validated on load, but programs may have changed since).
+func (s *Supervisor) shutdownOrder() []string {
+	order, err := topoSort(s.programs())
+	if err != nil {
+		log.Printf("shutdown order: %v", err)
+		order = append([]string(nil), s.names...)
+	}
+	reversed := make([]string, len(order))
+	for i, name := range order {
+		reversed[len(order)-1-i] = name
+	}
+	return reversed
+}
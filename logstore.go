@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+const defaultLogMaxBytes = 10 * 1024 * 1024
+
+// LogStore persists a process's combined stdout/stderr under
+// ConfigDir/logs/{name}.log, rotating it once it grows past maxBytes and
+// keeping up to backups old copies. It also serves the tail/offset reads
+// used to replay history to late-joining clients.
+type LogStore struct {
+	path     string
+	maxBytes int64
+	backups  int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newLogStore(dir, name string, maxBytes int64, backups int) (*LogStore, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultLogMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/%s.log", dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &LogStore{path: path, maxBytes: maxBytes, backups: backups, file: f, size: fi.Size()}, nil
+}
+
+func (ls *LogStore) Write(p []byte) (int, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	n, err := ls.file.Write(p)
+	ls.size += int64(n)
+	if ls.size >= ls.maxBytes {
+		ls.rotate()
+	}
+	return n, err
+}
+
+// rotate must be called with ls.mu held.
+func (ls *LogStore) rotate() {
+	ls.file.Close()
+
+	if ls.backups == 0 {
+		// Nothing to keep a backup copy in: just truncate the active
+		// file, otherwise reopening in O_APPEND mode would leave its
+		// old contents in place and LogMaxBytes would never actually
+		// be enforced.
+		f, err := os.OpenFile(ls.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			ls.file, ls.size = nil, 0
+			return
+		}
+		ls.file, ls.size = f, 0
+		return
+	}
+
+	for i := ls.backups; i > 0; i-- {
+		older := fmt.Sprintf("%s.%d", ls.path, i)
+		newer := ls.path
+		if i > 1 {
+			newer = fmt.Sprintf("%s.%d", ls.path, i-1)
+		}
+		os.Rename(newer, older)
+	}
+	f, err := os.OpenFile(ls.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		ls.file, ls.size = nil, 0
+		return
+	}
+	ls.file, ls.size = f, 0
+}
+
+func (ls *LogStore) Close() error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.file == nil {
+		return nil
+	}
+	return ls.file.Close()
+}
+
+// Tail returns the last n lines currently on disk. n <= 0 returns
+// everything.
+func (ls *LogStore) Tail(n int) ([]string, error) {
+	data, err := ls.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// Since returns the bytes written after offset.
+func (ls *LogStore) Since(offset int64) ([]byte, error) {
+	f, err := os.Open(ls.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(f)
+}
+
+func (ls *LogStore) readAll() ([]byte, error) {
+	return ioutil.ReadFile(ls.path)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricsSampleInterval = 5 * time.Second
+
+type procSample struct {
+	cpuSeconds float64
+	memBytes   uint64
+}
+
+// metricsCollector backs the /metrics endpoint: a background goroutine
+// periodically samples /proc/<pid> for every running process, while
+// gauges derived from live Process/event state are computed on scrape.
+type metricsCollector struct {
+	s *Supervisor
+
+	mu         sync.Mutex
+	eventTotal map[string]uint64
+	samples    map[string]procSample
+
+	stop chan struct{}
+}
+
+func newMetricsCollector(s *Supervisor) *metricsCollector {
+	return &metricsCollector{
+		s:          s,
+		eventTotal: make(map[string]uint64),
+		samples:    make(map[string]procSample),
+	}
+}
+
+// Start begins the periodic /proc sampling goroutine.
+func (m *metricsCollector) Start() {
+	m.stop = make(chan struct{})
+	go m.run(m.stop)
+}
+
+// Stop ends the sampling goroutine.
+func (m *metricsCollector) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+func (m *metricsCollector) run(stop chan struct{}) {
+	ticker := time.NewTicker(metricsSampleInterval)
+	defer ticker.Stop()
+	m.sampleAll()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.sampleAll()
+		}
+	}
+}
+
+func (m *metricsCollector) sampleAll() {
+	for _, proc := range m.s.procs() {
+		_, pid, _, _ := proc.Snapshot()
+		if pid == 0 {
+			continue
+		}
+		cpu, mem, err := readProcStat(pid)
+		if err != nil {
+			continue
+		}
+		m.mu.Lock()
+		m.samples[proc.Name] = procSample{cpuSeconds: cpu, memBytes: mem}
+		m.mu.Unlock()
+	}
+}
+
+// recordEvent bumps the counter for an event type, as returned by
+// classifyEvent.
+func (m *metricsCollector) recordEvent(eventType string) {
+	m.mu.Lock()
+	m.eventTotal[eventType]++
+	m.mu.Unlock()
+}
+
+// classifyEvent maps a free-form broadcastEvent message to the coarse
+// "type" label used by gosuv_events_total.
+func classifyEvent(event string) string {
+	switch {
+	case strings.Contains(event, "state:"):
+		return "state_change"
+	case strings.Contains(event, "cron triggered"):
+		return "cron"
+	case strings.Contains(event, "group"):
+		return "group"
+	case strings.Contains(event, "added"):
+		return "added"
+	case strings.Contains(event, "deleted"):
+		return "deleted"
+	case strings.Contains(event, "update"):
+		return "update"
+	case strings.Contains(event, "reload"):
+		return "reload"
+	default:
+		return "other"
+	}
+}
+
+// WriteTo renders every metric in Prometheus exposition format.
+func (m *metricsCollector) WriteTo(w io.Writer) {
+	fmt.Fprintln(w, "# HELP gosuv_process_state Process FSM state (1 for the current state, 0 otherwise)")
+	fmt.Fprintln(w, "# TYPE gosuv_process_state gauge")
+	fmt.Fprintln(w, "# HELP gosuv_process_restarts_total Cumulative auto-restarts of a process")
+	fmt.Fprintln(w, "# TYPE gosuv_process_restarts_total counter")
+	fmt.Fprintln(w, "# HELP gosuv_process_uptime_seconds Seconds since the process last entered the running state")
+	fmt.Fprintln(w, "# TYPE gosuv_process_uptime_seconds gauge")
+	fmt.Fprintln(w, "# HELP gosuv_process_cpu_seconds_total Cumulative user+system CPU time, from /proc/<pid>/stat")
+	fmt.Fprintln(w, "# TYPE gosuv_process_cpu_seconds_total counter")
+	fmt.Fprintln(w, "# HELP gosuv_process_memory_bytes Resident set size, from /proc/<pid>/status")
+	fmt.Fprintln(w, "# TYPE gosuv_process_memory_bytes gauge")
+
+	m.mu.Lock()
+	samples := make(map[string]procSample, len(m.samples))
+	for k, v := range m.samples {
+		samples[k] = v
+	}
+	m.mu.Unlock()
+
+	for _, proc := range m.s.procs() {
+		state, _, runningSince, restarts := proc.Snapshot()
+		for _, st := range allStates {
+			v := 0
+			if st == state {
+				v = 1
+			}
+			fmt.Fprintf(w, "gosuv_process_state{name=%q,state=%q} %d\n", proc.Name, st, v)
+		}
+		fmt.Fprintf(w, "gosuv_process_restarts_total{name=%q} %d\n", proc.Name, restarts)
+
+		uptime := 0.0
+		if !runningSince.IsZero() {
+			uptime = time.Since(runningSince).Seconds()
+		}
+		fmt.Fprintf(w, "gosuv_process_uptime_seconds{name=%q} %.3f\n", proc.Name, uptime)
+
+		if sample, ok := samples[proc.Name]; ok {
+			fmt.Fprintf(w, "gosuv_process_cpu_seconds_total{name=%q} %.3f\n", proc.Name, sample.cpuSeconds)
+			fmt.Fprintf(w, "gosuv_process_memory_bytes{name=%q} %d\n", proc.Name, sample.memBytes)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP gosuv_events_total Events broadcast on the supervisor event bus, by type")
+	fmt.Fprintln(w, "# TYPE gosuv_events_total counter")
+	m.mu.Lock()
+	for eventType, count := range m.eventTotal {
+		fmt.Fprintf(w, "gosuv_events_total{type=%q} %d\n", eventType, count)
+	}
+	m.mu.Unlock()
+}
+
+// readProcStat reads cumulative CPU time (seconds) and resident memory
+// (bytes) for pid out of /proc.
+func readProcStat(pid int) (cpuSeconds float64, memBytes uint64, err error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The comm field (2nd) is parenthesized and may itself contain
+	// spaces, so split after its closing paren rather than on fields[1].
+	line := string(data)
+	idx := strings.LastIndex(line, ")")
+	if idx < 0 {
+		return 0, 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	fields := strings.Fields(line[idx+1:])
+	// fields[0] here is overall field 3 (state); utime/stime are overall
+	// fields 14/15, i.e. fields[11] and fields[12].
+	if len(fields) < 13 {
+		return 0, 0, fmt.Errorf("unexpected /proc/%d/stat field count", pid)
+	}
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	const clockTicksPerSec = 100.0
+	cpuSeconds = (utime + stime) / clockTicksPerSec
+
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return cpuSeconds, 0, nil
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) >= 2 {
+			if kb, err := strconv.ParseUint(parts[1], 10, 64); err == nil {
+				memBytes = kb * 1024
+			}
+		}
+		break
+	}
+	return cpuSeconds, memBytes, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultStopTimeout = 10 * time.Second
+
+// allStates lists every FSMState, in a stable order, for metrics
+// enumeration.
+var allStates = []FSMState{StateStopped, StateStarting, StateRunning, StateStopping, StateFatal}
+
+// FSMState is a Process lifecycle state.
+type FSMState string
+
+const (
+	StateStopped  FSMState = "stopped"
+	StateStarting FSMState = "starting"
+	StateRunning  FSMState = "running"
+	StateStopping FSMState = "stopping"
+	StateFatal    FSMState = "fatal"
+)
+
+// FSMEvent drives Process.Operate.
+type FSMEvent string
+
+const (
+	StartEvent FSMEvent = "start"
+	StopEvent  FSMEvent = "stop"
+)
+
+// Process wraps a running (or not yet running) instance of a Program.
+type Process struct {
+	Name        string
+	Program     Program
+	Output      *WriteBroadcaster
+	Logs        *LogStore
+	StateChange func(oldState, newState FSMState)
+
+	mu           sync.Mutex
+	state        FSMState
+	cmd          *exec.Cmd
+	retries      int
+	restarts     uint64
+	pid          int
+	runningSince time.Time
+}
+
+// MarshalJSON exposes the process name, program definition and current
+// state; StateChange and the internal mutex/cmd are not serializable.
+func (p *Process) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	state := p.state
+	p.mu.Unlock()
+	return json.Marshal(struct {
+		Name    string   `json:"name"`
+		Program Program  `json:"program"`
+		State   FSMState `json:"state"`
+	}{p.Name, p.Program, state})
+}
+
+// NewProcess creates a Process for pg in the Stopped state.
+func NewProcess(pg Program) *Process {
+	return &Process{
+		Name:        pg.Name,
+		Program:     pg,
+		Output:      NewWriteBroadcaster(1024),
+		StateChange: func(oldState, newState FSMState) {},
+		state:       StateStopped,
+	}
+}
+
+// IsRunning reports whether the process is starting or running.
+func (p *Process) IsRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state == StateRunning || p.state == StateStarting
+}
+
+// State returns the current FSM state.
+func (p *Process) State() FSMState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Snapshot returns the process's state, OS pid (0 if not running), the
+// time it last entered the Running state, and its cumulative restart
+// count, for reporting via /metrics.
+func (p *Process) Snapshot() (state FSMState, pid int, runningSince time.Time, restarts uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.pid, p.runningSince, p.restarts
+}
+
+func (p *Process) setState(newState FSMState) {
+	p.mu.Lock()
+	old := p.state
+	p.state = newState
+	p.mu.Unlock()
+	if old != newState {
+		p.StateChange(old, newState)
+	}
+}
+
+// Operate applies a lifecycle event to the process.
+func (p *Process) Operate(event FSMEvent) {
+	switch event {
+	case StartEvent:
+		go p.startCommand()
+	case StopEvent:
+		p.mu.Lock()
+		cmd := p.cmd
+		p.mu.Unlock()
+		p.setState(StateStopping)
+		if cmd == nil || cmd.Process == nil {
+			return
+		}
+		cmd.Process.Signal(stopSignal(p.Program.StopSignal))
+		timeout := time.Duration(p.Program.StopTimeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultStopTimeout
+		}
+		go func() {
+			time.Sleep(timeout)
+			if p.State() != StateStopped {
+				cmd.Process.Kill()
+			}
+		}()
+	}
+}
+
+// stopSignal maps a Program.StopSignal name ("TERM", "SIGTERM", "KILL",
+// "INT", "HUP", "USR1", "USR2") to its os.Signal, defaulting to SIGTERM.
+func stopSignal(name string) os.Signal {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "KILL":
+		return syscall.SIGKILL
+	case "INT":
+		return syscall.SIGINT
+	case "HUP":
+		return syscall.SIGHUP
+	case "USR1":
+		return syscall.SIGUSR1
+	case "USR2":
+		return syscall.SIGUSR2
+	default:
+		return syscall.SIGTERM
+	}
+}
+
+// lookupCredential resolves Program.User to the syscall.Credential the
+// child process should run as.
+func lookupCredential(name string) (*syscall.Credential, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, nil
+}
+
+// openRedirects builds the stdout/stderr writers for a run: always the
+// in-memory broadcaster and on-disk LogStore, plus Program.Stdout/Stderr
+// files when configured. The returned close func closes any files opened.
+func (p *Process) openRedirects() (stdout, stderr io.Writer, closeFiles func()) {
+	base := []io.Writer{p.Output}
+	if p.Logs != nil {
+		base = append(base, p.Logs)
+	}
+
+	var files []*os.File
+	open := func(path string) io.Writer {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil
+		}
+		files = append(files, f)
+		return f
+	}
+
+	outWriters := append([]io.Writer{}, base...)
+	if p.Program.Stdout != "" {
+		if w := open(p.Program.Stdout); w != nil {
+			outWriters = append(outWriters, w)
+		}
+	}
+	errWriters := append([]io.Writer{}, base...)
+	switch {
+	case p.Program.Stderr != "" && p.Program.Stderr == p.Program.Stdout:
+		errWriters = outWriters
+	case p.Program.Stderr != "":
+		if w := open(p.Program.Stderr); w != nil {
+			errWriters = append(errWriters, w)
+		}
+	}
+
+	return io.MultiWriter(outWriters...), io.MultiWriter(errWriters...), func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+}
+
+func (p *Process) startCommand() {
+	p.setState(StateStarting)
+	cmd := exec.Command("sh", "-c", p.Program.Command)
+	cmd.Dir = p.Program.Dir
+	cmd.Env = p.Program.Environ
+
+	if p.Program.User != "" {
+		cred, err := lookupCredential(p.Program.User)
+		if err != nil {
+			p.setState(StateFatal)
+			return
+		}
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+
+	stdout, stderr, closeRedirects := p.openRedirects()
+	defer closeRedirects()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	p.mu.Lock()
+	p.cmd = cmd
+	p.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		p.setState(StateFatal)
+		return
+	}
+	p.mu.Lock()
+	p.pid = cmd.Process.Pid
+	p.runningSince = time.Now()
+	p.mu.Unlock()
+	p.setState(StateRunning)
+	err := cmd.Wait()
+	p.mu.Lock()
+	p.pid = 0
+	p.runningSince = time.Time{}
+	p.mu.Unlock()
+
+	// An exit caused by StopEvent is never retried, whatever its status.
+	if p.State() == StateStopping {
+		p.retries = 0
+		p.setState(StateStopped)
+		return
+	}
+
+	// A one-shot program is never retried: a clean exit moves it to the
+	// terminal Stopped state, the same place manual stops land, so the
+	// cron scheduler (or a future trigger) is the only thing that can
+	// start it again. A crash still surfaces as Fatal so it's
+	// distinguishable from a clean run.
+	if p.Program.OneShot {
+		if err != nil {
+			p.setState(StateFatal)
+			return
+		}
+		p.setState(StateStopped)
+		return
+	}
+	// Cron-scheduled programs are driven by the CronScheduler, not the
+	// auto-restart FSM: a failure waits for the next tick instead of
+	// being retried here too.
+	if err != nil && p.Program.Cron == "" && p.retries < p.Program.StartRetries {
+		p.retries++
+		p.mu.Lock()
+		p.restarts++
+		p.mu.Unlock()
+		go p.startCommand()
+		return
+	}
+	p.retries = 0
+	p.setState(StateStopped)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robfig/cron"
+)
+
+// Program describes how a single managed process should be started and
+// supervised. Programs are persisted to and loaded from programs.yml.
+type Program struct {
+	Name         string   `yaml:"name" json:"name"`
+	Command      string   `yaml:"command" json:"command"`
+	Dir          string   `yaml:"dir" json:"dir"`
+	Environ      []string `yaml:"environ,omitempty" json:"environ,omitempty"`
+	User         string   `yaml:"user,omitempty" json:"user,omitempty"`
+	StartAuto    bool     `yaml:"start_auto" json:"start_auto"`
+	StartRetries int      `yaml:"start_retries" json:"start_retries"`
+	StartSeconds int      `yaml:"start_seconds,omitempty" json:"start_seconds,omitempty"`
+
+	// Stdout/Stderr, when set, redirect the process's output to a file
+	// path in addition to the in-memory broadcaster and LogStore.
+	Stdout string `yaml:"stdout,omitempty" json:"stdout,omitempty"`
+	Stderr string `yaml:"stderr,omitempty" json:"stderr,omitempty"`
+
+	// StopSignal is the signal sent on StopEvent (default SIGTERM).
+	// StopTimeout is how long, in seconds, to wait for it to take effect
+	// before the process is killed.
+	StopSignal  string `yaml:"stop_signal,omitempty" json:"stop_signal,omitempty"`
+	StopTimeout int    `yaml:"stop_timeout,omitempty" json:"stop_timeout,omitempty"`
+
+	// Cron, when set, is a robfig/cron schedule ("@every 30s" or a
+	// standard 5-field expression). Scheduled programs are driven by the
+	// supervisor's CronScheduler instead of StartAuto/StartRetries.
+	Cron string `yaml:"cron,omitempty" json:"cron,omitempty"`
+	// OneShot marks a Cron program whose clean exit should not be
+	// retried: it moves to the terminal Stopped state until the next
+	// scheduled tick or manual trigger.
+	OneShot bool `yaml:"one_shot,omitempty" json:"one_shot,omitempty"`
+
+	// LogMaxBytes caps the on-disk log file size before it is rotated
+	// (default 10MiB). LogBackups is how many rotated copies to keep.
+	LogMaxBytes int64 `yaml:"log_max_bytes,omitempty" json:"log_max_bytes,omitempty"`
+	LogBackups  int   `yaml:"log_backups,omitempty" json:"log_backups,omitempty"`
+
+	// Group names the deployment group this program belongs to; see
+	// Supervisor.startGroup/stopGroup. DependsOn lists program names
+	// (any group) this one depends on. startGroup starts a group's own
+	// members in dependency order and stopGroup and Close stop dependents
+	// first; a dependency outside the group is not started by startGroup,
+	// and starting a single program does not start its dependencies.
+	Group     string   `yaml:"group,omitempty" json:"group,omitempty"`
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+}
+
+// ValidationError describes one invalid field on a Program.
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is a non-empty list of ValidationError, returned by
+// Program.Check so callers can surface per-field problems instead of a
+// single flat string.
+type ValidationErrors []ValidationError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Check validates a program definition before it is accepted by the
+// supervisor, returning every problem found rather than stopping at the
+// first one.
+func (p *Program) Check() error {
+	var errs ValidationErrors
+	if p.Name == "" {
+		errs = append(errs, ValidationError{"name", "program name required"})
+	}
+	if p.Command == "" {
+		errs = append(errs, ValidationError{"command", "program command required"})
+	}
+	if p.Cron != "" {
+		if _, err := cron.ParseStandard(p.Cron); err != nil {
+			errs = append(errs, ValidationError{"cron", err.Error()})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+func TestProgramYAMLRoundTrip(t *testing.T) {
+	pg := Program{
+		Name:         "web",
+		Command:      "python manage.py runserver",
+		Dir:          "/srv/app",
+		Environ:      []string{"DEBUG=1"},
+		User:         "www-data",
+		StartAuto:    true,
+		StartRetries: 3,
+		StartSeconds: 2,
+		Stdout:       "/var/log/web.out",
+		Stderr:       "/var/log/web.err",
+		StopSignal:   "TERM",
+		StopTimeout:  10,
+		Cron:         "@every 30s",
+		OneShot:      true,
+		LogMaxBytes:  1024,
+		LogBackups:   2,
+	}
+
+	data, err := yaml.Marshal(pg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got Program
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(pg, got) {
+		t.Fatalf("round-trip mismatch:\n want %+v\n got  %+v", pg, got)
+	}
+}
+
+func TestProgramCheck(t *testing.T) {
+	cases := []struct {
+		name    string
+		pg      Program
+		wantErr bool
+	}{
+		{"valid", Program{Name: "web", Command: "true"}, false},
+		{"missing name", Program{Command: "true"}, true},
+		{"missing command", Program{Name: "web"}, true},
+		{"bad cron", Program{Name: "web", Command: "true", Cron: "not a schedule"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.pg.Check()
+			if c.wantErr && err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.wantErr && err != nil {
+				if _, ok := err.(ValidationErrors); !ok {
+					t.Fatalf("expected ValidationErrors, got %T", err)
+				}
+			}
+		})
+	}
+}
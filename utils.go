@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"os/user"
+)
+
+// UserHomeDir returns the current user's home directory, falling back to
+// $HOME when the os/user lookup fails.
+func UserHomeDir() string {
+	if u, err := user.Current(); err == nil && u.HomeDir != "" {
+		return u.HomeDir
+	}
+	return os.Getenv("HOME")
+}
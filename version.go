@@ -0,0 +1,4 @@
+package main
+
+// Version is overridden at release build time via -ldflags.
+var Version = "dev"
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/qiniu/log"
+)
+
+const (
+	configPollInterval = 200 * time.Millisecond
+	configDebounce     = 500 * time.Millisecond
+)
+
+// watchConfig polls programPath for mtime changes and reloads the database
+// when it advances, so editing programs.yml by hand behaves like a
+// supervisord include-dir reload without needing a client to hit
+// /api/reload. Rapid successive writes are debounced so a half-written
+// file is never parsed; on a parse error the previous in-memory state is
+// kept and the error is broadcast instead.
+func (s *Supervisor) watchConfig(stop chan struct{}) {
+	var lastMod time.Time
+	if fi, err := os.Stat(s.programPath()); err == nil {
+		lastMod = fi.ModTime()
+	}
+
+	var lastChangeAt time.Time
+	pending := false
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(s.programPath())
+			if err != nil {
+				continue
+			}
+			if fi.ModTime().After(lastMod) {
+				lastMod = fi.ModTime()
+				lastChangeAt = time.Now()
+				pending = true
+			}
+			if !pending || time.Since(lastChangeAt) < configDebounce {
+				continue
+			}
+			pending = false
+			if err := s.loadDB(); err != nil {
+				log.Printf("reload programs.yml failed: %v", err)
+				s.broadcastEvent("reload failed: " + err.Error())
+				continue
+			}
+			s.broadcastEvent("programs.yml reloaded")
+		}
+	}
+}
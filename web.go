@@ -39,6 +39,10 @@ type Supervisor struct {
 	procMap map[string]*Process
 	mu      sync.Mutex
 	eventB  *WriteBroadcaster
+	cron    *CronScheduler
+	metrics *metricsCollector
+
+	watchStop chan struct{}
 }
 
 func (s *Supervisor) programs() []Program {
@@ -63,6 +67,12 @@ func (s *Supervisor) programPath() string {
 
 func (s *Supervisor) newProcess(pg Program) *Process {
 	p := NewProcess(pg)
+	logDir := filepath.Join(s.ConfigDir, "logs")
+	if ls, err := newLogStore(logDir, pg.Name, pg.LogMaxBytes, pg.LogBackups); err == nil {
+		p.Logs = ls
+	} else {
+		log.Printf("open log store for %s: %v", pg.Name, err)
+	}
 	origFunc := p.StateChange
 	p.StateChange = func(oldState, newState FSMState) {
 		s.broadcastEvent(fmt.Sprintf("%s state: %s -> %s", p.Name, string(oldState), string(newState)))
@@ -72,6 +82,9 @@ func (s *Supervisor) newProcess(pg Program) *Process {
 }
 
 func (s *Supervisor) broadcastEvent(event string) {
+	if s.metrics != nil {
+		s.metrics.recordEvent(classifyEvent(event))
+	}
 	s.eventB.Write([]byte(event))
 }
 
@@ -130,6 +143,9 @@ func (s *Supervisor) addOrUpdateProgram(pg Program) error {
 			newProc := s.newProcess(pg)
 			s.procMap[pg.Name] = newProc
 			s.pgMap[pg.Name] = pg // update origin
+			if origProc.Logs != nil {
+				origProc.Logs.Close()
+			}
 			if isRunning {
 				newProc.Operate(StartEvent)
 			}
@@ -141,12 +157,18 @@ func (s *Supervisor) addOrUpdateProgram(pg Program) error {
 		s.procMap[pg.Name] = s.newProcess(pg)
 		s.broadcastEvent(pg.Name + " added")
 	}
+	if s.cron != nil {
+		if err := s.cron.schedule(pg); err != nil {
+			log.Println(err)
+		}
+	}
 	return nil
 }
 
 // Check
 // - Yaml format
 // - Duplicated program
+// - DependsOn graph has no cycles
 func (s *Supervisor) readConfigFromDB() (pgs []Program, err error) {
 	data, err := ioutil.ReadFile(s.programPath())
 	if err != nil {
@@ -163,6 +185,9 @@ func (s *Supervisor) readConfigFromDB() (pgs []Program, err error) {
 		}
 		visited[pg.Name] = true
 	}
+	if _, err = topoSort(pgs); err != nil {
+		return nil, err
+	}
 	return
 }
 
@@ -190,8 +215,14 @@ func (s *Supervisor) loadDB() error {
 		name := pg.Name
 		log.Printf("stop before delete program: %s", name)
 		s.stopAndWait(name)
+		if proc, ok := s.procMap[name]; ok && proc.Logs != nil {
+			proc.Logs.Close()
+		}
 		delete(s.procMap, name)
 		delete(s.pgMap, name)
+		if s.cron != nil {
+			s.cron.unschedule(name)
+		}
 		s.broadcastEvent(pg.Name + " deleted")
 	}
 	return nil
@@ -282,8 +313,50 @@ func (s *Supervisor) hReload(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ProgramStatus is the GET /api/programs view of a process: its live state
+// plus, for cron-scheduled programs, the next and last run timestamps.
+type ProgramStatus struct {
+	*Process
+	NextRun *time.Time `json:"next_run,omitempty"`
+	LastRun *time.Time `json:"last_run,omitempty"`
+}
+
+// MarshalJSON flattens the embedded Process alongside NextRun/LastRun.
+// Without it, Process's own pointer-receiver MarshalJSON would be
+// promoted and used instead, silently dropping the two timestamps.
+func (st ProgramStatus) MarshalJSON() ([]byte, error) {
+	procJSON, err := json.Marshal(st.Process)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(procJSON, &fields); err != nil {
+		return nil, err
+	}
+	if st.NextRun != nil {
+		fields["next_run"] = st.NextRun
+	}
+	if st.LastRun != nil {
+		fields["last_run"] = st.LastRun
+	}
+	return json.Marshal(fields)
+}
+
 func (s *Supervisor) hGetProgram(w http.ResponseWriter, r *http.Request) {
-	data, err := json.Marshal(s.procs())
+	statuses := make([]ProgramStatus, 0, len(s.names))
+	for _, proc := range s.procs() {
+		st := ProgramStatus{Process: proc}
+		if s.cron != nil {
+			if t, ok := s.cron.nextRun(proc.Name); ok {
+				st.NextRun = &t
+			}
+			if t, ok := s.cron.lastRunTime(proc.Name); ok {
+				st.LastRun = &t
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	data, err := json.Marshal(statuses)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -292,49 +365,139 @@ func (s *Supervisor) hGetProgram(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (s *Supervisor) hAddProgram(w http.ResponseWriter, r *http.Request) {
-	retries, err := strconv.Atoi(r.FormValue("retries"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
-		return
+// hTriggerProgram manually fires a (typically cron-scheduled) program,
+// identical to what the scheduler does at its next tick.
+func (s *Supervisor) hTriggerProgram(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var data []byte
+	if s.cron == nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 1,
+			"error":  "scheduler not running",
+		})
+	} else if err := s.cron.trigger(name); err != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 1,
+			"error":  err.Error(),
+		})
+	} else {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 0,
+			"name":   name,
+		})
 	}
-	pg := Program{
-		Name:         r.FormValue("name"),
-		Command:      r.FormValue("command"),
-		Dir:          r.FormValue("dir"),
-		StartAuto:    r.FormValue("autostart") == "on",
-		StartRetries: retries,
-		// TODO: missing other values
+	w.Write(data)
+}
+
+// decodeProgram reads a full Program out of the request JSON body.
+func decodeProgram(r *http.Request) (Program, error) {
+	var pg Program
+	if err := json.NewDecoder(r.Body).Decode(&pg); err != nil {
+		return pg, err
 	}
 	if pg.Dir == "" {
 		pg.Dir = "/"
 	}
-	if err := pg.Check(); err != nil {
+	return pg, nil
+}
+
+// writeCheckError renders a Program.Check failure, preserving the
+// per-field ValidationErrors shape when available instead of flattening
+// it to a single string.
+func (s *Supervisor) writeCheckError(w http.ResponseWriter, err error) {
+	resp := JSONResponse{Status: 1}
+	if verrs, ok := err.(ValidationErrors); ok {
+		resp.Value = verrs
+	} else {
+		resp.Value = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	bytes, _ := json.Marshal(resp)
+	w.Write(bytes)
+}
+
+func (s *Supervisor) hAddProgram(w http.ResponseWriter, r *http.Request) {
+	pg, err := decodeProgram(r)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	var data []byte
+	if err := pg.Check(); err != nil {
+		s.writeCheckError(w, err)
+		return
+	}
 	if _, ok := s.pgMap[pg.Name]; ok {
-		data, _ = json.Marshal(map[string]interface{}{
-			"status": 1,
-			"error":  fmt.Sprintf("Program %s already exists", strconv.Quote(pg.Name)),
+		s.renderJSON(w, JSONResponse{
+			Status: 1,
+			Value:  fmt.Sprintf("Program %s already exists", strconv.Quote(pg.Name)),
 		})
-	} else {
-		if err := s.addOrUpdateProgram(pg); err != nil {
-			data, _ = json.Marshal(map[string]interface{}{
-				"status": 1,
-				"error":  err.Error(),
-			})
-		} else {
-			s.saveDB()
-			data, _ = json.Marshal(map[string]interface{}{
-				"status": 0,
-			})
+		return
+	}
+	if err := s.addOrUpdateProgram(pg); err != nil {
+		s.renderJSON(w, JSONResponse{Status: 1, Value: err.Error()})
+		return
+	}
+	s.saveDB()
+	s.renderJSON(w, JSONResponse{Status: 0, Value: pg.Name})
+}
+
+// hUpdateProgram replaces an existing program's full definition.
+func (s *Supervisor) hUpdateProgram(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	pg, err := decodeProgram(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if pg.Name == "" {
+		pg.Name = name
+	}
+	if pg.Name != name {
+		http.Error(w, "program name in body must match the URL", http.StatusBadRequest)
+		return
+	}
+	if err := pg.Check(); err != nil {
+		s.writeCheckError(w, err)
+		return
+	}
+	if err := s.addOrUpdateProgram(pg); err != nil {
+		s.renderJSON(w, JSONResponse{Status: 1, Value: err.Error()})
+		return
+	}
+	s.saveDB()
+	s.renderJSON(w, JSONResponse{Status: 0, Value: pg.Name})
+}
+
+// hDeleteProgram stops and removes a program entirely.
+func (s *Supervisor) hDeleteProgram(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, ok := s.pgMap[name]; !ok {
+		http.Error(w, fmt.Sprintf("Process %s not exists", strconv.Quote(name)), http.StatusNotFound)
+		return
+	}
+	s.stopAndWait(name)
+
+	s.mu.Lock()
+	if proc, ok := s.procMap[name]; ok && proc.Logs != nil {
+		proc.Logs.Close()
+	}
+	delete(s.pgMap, name)
+	delete(s.procMap, name)
+	for i, n := range s.names {
+		if n == name {
+			s.names = append(s.names[:i], s.names[i+1:]...)
+			break
 		}
 	}
-	w.Write(data)
+	s.mu.Unlock()
+
+	if s.cron != nil {
+		s.cron.unschedule(name)
+	}
+	s.saveDB()
+	s.broadcastEvent(name + " deleted")
+	s.renderJSON(w, JSONResponse{Status: 0, Value: name})
 }
 
 func (s *Supervisor) hStartProgram(w http.ResponseWriter, r *http.Request) {
@@ -375,6 +538,94 @@ func (s *Supervisor) hStopProgram(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// hGetProgramLog serves a program's persisted log: ?since=<byte offset>
+// returns everything written after that offset, otherwise ?tail=N (default
+// 100) returns the last N lines.
+func (s *Supervisor) hGetProgramLog(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	proc, ok := s.procMap[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Process %s not exists", strconv.Quote(name)), http.StatusNotFound)
+		return
+	}
+	if proc.Logs == nil {
+		http.Error(w, "log store not available", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if since := r.FormValue("since"); since != "" {
+		offset, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := proc.Logs.Since(offset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+		return
+	}
+	n := 100
+	if tail := r.FormValue("tail"); tail != "" {
+		if v, err := strconv.Atoi(tail); err == nil {
+			n = v
+		}
+	}
+	lines, err := proc.Logs.Tail(n)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// hStartGroup starts every member of a program group in dependency order.
+func (s *Supervisor) hStartGroup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var data []byte
+	if err := s.startGroup(name); err != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 1,
+			"error":  err.Error(),
+		})
+	} else {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 0,
+			"name":   name,
+		})
+	}
+	w.Write(data)
+}
+
+// hStopGroup stops every member of a program group in reverse dependency
+// order.
+func (s *Supervisor) hStopGroup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var data []byte
+	if err := s.stopGroup(name); err != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 1,
+			"error":  err.Error(),
+		})
+	} else {
+		data, _ = json.Marshal(map[string]interface{}{
+			"status": 0,
+			"name":   name,
+		})
+	}
+	w.Write(data)
+}
+
+// hMetrics serves the Prometheus exposition-format scrape target.
+func (s *Supervisor) hMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.WriteTo(w)
+}
+
 var upgrader = websocket.Upgrader{}
 
 func (s *Supervisor) wsEvents(w http.ResponseWriter, r *http.Request) {
@@ -385,14 +636,16 @@ func (s *Supervisor) wsEvents(w http.ResponseWriter, r *http.Request) {
 	}
 	defer c.Close()
 
-	ch := make(chan string, 0)
-	s.addStatusChangeListener(ch)
+	// By default a new listener gets replayed the buffered event
+	// history before live-tailing; ?skip=1 discards it.
+	skipHistory := r.FormValue("skip") == "1"
+	ch := s.eventB.NewChanStringWithHistory(r.RemoteAddr, skipHistory)
+	defer s.eventB.CloseWriter(r.RemoteAddr)
 	go func() {
 		for message := range ch {
 			// Question: type 1 ?
 			c.WriteMessage(1, []byte(message))
 		}
-		// s.eventB.RemoveListener(ch)
 	}()
 	for {
 		mt, message, err := c.ReadMessage()
@@ -426,6 +679,19 @@ func (s *Supervisor) wsLog(w http.ResponseWriter, r *http.Request) {
 	}
 	defer c.Close()
 
+	if n, err := strconv.Atoi(r.FormValue("replay")); err == nil && n > 0 && proc.Logs != nil {
+		lines, err := proc.Logs.Tail(n)
+		if err != nil {
+			log.Printf("replay log for %s: %v", name, err)
+		}
+		for _, line := range lines {
+			if err := c.WriteMessage(1, []byte(line+"\n")); err != nil {
+				proc.Output.CloseWriter(r.RemoteAddr)
+				return
+			}
+		}
+	}
+
 	for data := range proc.Output.NewChanString(r.RemoteAddr) {
 		err := c.WriteMessage(1, []byte(data))
 		if err != nil {
@@ -436,8 +702,23 @@ func (s *Supervisor) wsLog(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Supervisor) Close() {
+	if s.watchStop != nil {
+		close(s.watchStop)
+		s.watchStop = nil
+	}
+	if s.cron != nil {
+		s.cron.Stop()
+	}
+	if s.metrics != nil {
+		s.metrics.Stop()
+	}
+	for _, name := range s.shutdownOrder() {
+		s.stopAndWait(name)
+	}
 	for _, proc := range s.procMap {
-		s.stopAndWait(proc.Name)
+		if proc.Logs != nil {
+			proc.Logs.Close()
+		}
 	}
 	log.Println("server closed")
 }
@@ -464,11 +745,17 @@ func newSupervisorHandler() (hdlr http.Handler, err error) {
 		ConfigDir: defaultConfigDir,
 		pgMap:     make(map[string]Program, 0),
 		procMap:   make(map[string]*Process, 0),
-		eventB:    NewWriteBroadcaster(4 * 1024),
+		eventB:    NewWriteBroadcasterWithHistory(4*1024, 50),
 	}
+	suv.cron = newCronScheduler(suv)
 	if err = suv.loadDB(); err != nil {
 		return
 	}
+	suv.cron.Start()
+	suv.metrics = newMetricsCollector(suv)
+	suv.metrics.Start()
+	suv.watchStop = make(chan struct{})
+	go suv.watchConfig(suv.watchStop)
 	suv.catchExitSignal()
 
 	r := mux.NewRouter()
@@ -476,13 +763,21 @@ func newSupervisorHandler() (hdlr http.Handler, err error) {
 	r.HandleFunc("/settings/{name}", suv.hSetting)
 
 	r.HandleFunc("/api/status", suv.hStatus)
+	r.HandleFunc("/metrics", suv.hMetrics).Methods("GET")
 	r.HandleFunc("/api/shutdown", suv.hShutdown).Methods("POST")
 	r.HandleFunc("/api/reload", suv.hReload).Methods("POST")
 
 	r.HandleFunc("/api/programs", suv.hGetProgram).Methods("GET")
 	r.HandleFunc("/api/programs", suv.hAddProgram).Methods("POST")
+	r.HandleFunc("/api/programs/{name}", suv.hUpdateProgram).Methods("PUT")
+	r.HandleFunc("/api/programs/{name}", suv.hDeleteProgram).Methods("DELETE")
 	r.HandleFunc("/api/programs/{name}/start", suv.hStartProgram).Methods("POST")
 	r.HandleFunc("/api/programs/{name}/stop", suv.hStopProgram).Methods("POST")
+	r.HandleFunc("/api/programs/{name}/trigger", suv.hTriggerProgram).Methods("POST")
+	r.HandleFunc("/api/programs/{name}/log", suv.hGetProgramLog).Methods("GET")
+
+	r.HandleFunc("/api/groups/{name}/start", suv.hStartGroup).Methods("POST")
+	r.HandleFunc("/api/groups/{name}/stop", suv.hStopGroup).Methods("POST")
 
 	r.HandleFunc("/ws/events", suv.wsEvents)
 	r.HandleFunc("/ws/logs/{name}", suv.wsLog)